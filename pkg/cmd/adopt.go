@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	cli "github.com/spf13/cobra"
+	"github.com/timsolov/boilr/pkg/template"
+	"github.com/timsolov/boilr/pkg/util/exit"
+	"github.com/timsolov/boilr/pkg/util/validate"
+)
+
+var adoptDryRun bool
+
+// Adopt contains the cli-command for back-porting changes made to an
+// existing rendered project into the template it came from.
+var Adopt = &cli.Command{
+	Use:   "adopt <project-path> <template-path>",
+	Short: "Back-port changes from a project into the template it was generated from",
+	Run: func(c *cli.Command, args []string) {
+		if len(args) < 2 {
+			_ = c.Usage()
+			return
+		}
+
+		MustValidateArgs(args, []validate.Argument{
+			{Name: "project-path", Validate: validate.UnixPath},
+			{Name: "template-path", Validate: validate.UnixPath},
+		})
+
+		projectPath, templatePath := args[0], args[1]
+
+		adoptFn := template.Adopt
+		if adoptDryRun {
+			adoptFn = template.AdoptDryRun
+		}
+
+		changes, err := adoptFn(templatePath, projectPath)
+		if err != nil {
+			exit.Error(err)
+			return
+		}
+
+		for _, change := range changes {
+			fmt.Printf("%s %s\n", change.Action, change.TemplateFile)
+		}
+
+		exit.OK(fmt.Sprintf("Adopted %d file(s)", len(changes)))
+	},
+}
+
+func init() {
+	Adopt.Flags().BoolVar(&adoptDryRun, "dry-run", false, "print the intended changes without touching disk")
+}
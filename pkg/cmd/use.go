@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	cli "github.com/spf13/cobra"
+	"github.com/timsolov/boilr/pkg/template"
+	"github.com/timsolov/boilr/pkg/util/exit"
+	"github.com/timsolov/boilr/pkg/util/validate"
+)
+
+var (
+	useDefaults bool
+	useWatch    bool
+	useDryRun   bool
+)
+
+// Use contains the cli-command for executing a local project template.
+var Use = &cli.Command{
+	Use:   "use <template-path> [target-path]",
+	Short: "Execute a local project template",
+	Run: func(c *cli.Command, args []string) {
+		if len(args) == 0 {
+			_ = c.Usage()
+			return
+		}
+
+		MustValidateArgs(args, []validate.Argument{
+			{Name: "template-path", Validate: validate.UnixPath},
+		})
+
+		templatePath := args[0]
+
+		target := "."
+		if len(args) > 1 {
+			target = args[1]
+		}
+
+		tmpl, err := template.Get(templatePath)
+		if err != nil {
+			exit.Error(err)
+			return
+		}
+
+		if useDefaults {
+			tmpl.UseDefaultValues()
+		}
+
+		if useDryRun {
+			plan, err := tmpl.Plan(target)
+			if err != nil {
+				exit.Error(err)
+				return
+			}
+
+			for _, entry := range plan.Entries {
+				fmt.Printf("%s %s\n", entry.Action, entry.Path)
+			}
+
+			if diff := plan.Diff(); diff != "" {
+				fmt.Print(diff)
+			}
+
+			exit.OK("Dry run complete, nothing was written")
+			return
+		}
+
+		if !useWatch {
+			if err := tmpl.Execute(target); err != nil {
+				exit.Error(err)
+				return
+			}
+
+			exit.OK("Project has been created")
+			return
+		}
+
+		stop := make(chan struct{})
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+
+		go func() {
+			<-sig
+			close(stop)
+		}()
+
+		if err := tmpl.ExecuteWatch(target, stop); err != nil {
+			exit.Error(err)
+			return
+		}
+
+		exit.OK("Stopped watching")
+	},
+}
+
+func init() {
+	Use.Flags().BoolVar(&useDefaults, "use-defaults", false, "use default values instead of prompting")
+	Use.Flags().BoolVar(&useWatch, "watch", false, "keep re-rendering into target as the template's files change")
+	Use.Flags().BoolVar(&useDryRun, "dry-run", false, "print what would be created/updated without writing anything")
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 
 	cli "github.com/spf13/cobra"
+	"github.com/timsolov/boilr/pkg/template"
 	"github.com/timsolov/boilr/pkg/util/exit"
 	"github.com/timsolov/boilr/pkg/util/validate"
 )
@@ -13,9 +15,11 @@ var (
 	ErrTemplateInvalid = errors.New("validate: given template is invalid")
 )
 
+var validateDryRun bool
+
 // Validate contains the cli-command for validating templates.
 var Validate = &cli.Command{
-	Use:   "validate <template-path>",
+	Use:   "validate <template-path> [target-path]",
 	Short: "Validate a local project template",
 	Run: func(c *cli.Command, args []string) {
 		if len(args) == 0 {
@@ -31,6 +35,65 @@ var Validate = &cli.Command{
 
 		MustValidateTemplate(templatePath)
 
+		if err := template.CheckDependencies(templatePath); err != nil {
+			exit.Error(err)
+			return
+		}
+
+		if validateDryRun {
+			reportDryRun(templatePath, args)
+		}
+
 		exit.OK("Template is valid")
 	},
 }
+
+func init() {
+	Validate.Flags().BoolVar(&validateDryRun, "dry-run", false, "fully execute the template with default values and report what would happen")
+}
+
+// reportDryRun executes templatePath with default values against an
+// in-memory plan (or, when a target-path argument is given, diffs it
+// against that existing directory) and prints unresolved variables, unused
+// context keys, skipped files and a unified diff of anything that would
+// change.
+func reportDryRun(templatePath string, args []string) {
+	tmpl, err := template.Get(templatePath)
+	if err != nil {
+		exit.Error(err)
+		return
+	}
+
+	tmpl.UseDefaultValues()
+
+	target := "."
+	if len(args) > 1 {
+		target = args[1]
+	}
+
+	plan, err := tmpl.Plan(target)
+	if err != nil {
+		exit.Error(err)
+		return
+	}
+
+	for _, name := range plan.UnresolvedVariables {
+		fmt.Printf("unresolved variable: %s\n", name)
+	}
+
+	for _, name := range plan.UnusedContextKeys {
+		fmt.Printf("unused context key: %s\n", name)
+	}
+
+	for _, entry := range plan.Entries {
+		if entry.Action == template.ActionSkip && entry.Reason != "" && entry.Reason != "unchanged" {
+			fmt.Printf("skip %s: %s\n", entry.Path, entry.Reason)
+		}
+	}
+
+	if len(args) > 1 {
+		if diff := plan.Diff(); diff != "" {
+			fmt.Print(diff)
+		}
+	}
+}
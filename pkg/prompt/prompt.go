@@ -0,0 +1,156 @@
+// Package prompt asks the user for template variable values on stdin.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Option configures a prompt created by New.
+type Option func(*prompt)
+
+// WithLabel overrides the text shown to the user (defaults to the raw key name).
+func WithLabel(label string) Option {
+	return func(p *prompt) {
+		if label != "" {
+			p.label = label
+		}
+	}
+}
+
+// WithHelp attaches help text that is printed when the user answers with "?".
+func WithHelp(help string) Option {
+	return func(p *prompt) { p.help = help }
+}
+
+type prompt struct {
+	name  string
+	val   interface{}
+	label string
+	help  string
+}
+
+// New returns a thunk that, on first invocation, asks the user for a value
+// for name (falling back to val when the user just presses enter) and
+// memoizes the answer for any subsequent calls.
+func New(name string, val interface{}, opts ...Option) func() interface{} {
+	p := &prompt{name: name, val: val, label: name}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	var (
+		answered bool
+		answer   interface{}
+	)
+
+	return func() interface{} {
+		if !answered {
+			answer = p.ask()
+			answered = true
+		}
+
+		return answer
+	}
+}
+
+func (p *prompt) ask() interface{} {
+	reader := bufio.NewReader(os.Stdin)
+
+	switch val := p.val.(type) {
+	case []interface{}:
+		return p.askChoice(reader, val)
+	case bool:
+		return p.askBool(reader, val)
+	default:
+		return p.askString(reader, val)
+	}
+}
+
+func (p *prompt) askString(reader *bufio.Reader, def interface{}) interface{} {
+	for {
+		p.printQuestion(def)
+
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if line == "?" {
+			p.printHelp()
+			continue
+		}
+
+		if line == "" {
+			return def
+		}
+
+		return line
+	}
+}
+
+func (p *prompt) askBool(reader *bufio.Reader, def bool) interface{} {
+	for {
+		p.printQuestion(def)
+
+		line, _ := reader.ReadString('\n')
+		line = strings.ToLower(strings.TrimSpace(line))
+
+		switch line {
+		case "?":
+			p.printHelp()
+		case "":
+			return def
+		case "y", "yes", "true":
+			return true
+		case "n", "no", "false":
+			return false
+		}
+	}
+}
+
+func (p *prompt) askChoice(reader *bufio.Reader, choices []interface{}) interface{} {
+	for {
+		p.printQuestion(choices[0])
+		for i, choice := range choices {
+			fmt.Printf("  %d) %v\n", i+1, choice)
+		}
+
+		line, _ := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if line == "?" {
+			p.printHelp()
+			continue
+		}
+
+		if line == "" {
+			return choices[0]
+		}
+
+		for i, choice := range choices {
+			if line == fmt.Sprintf("%d", i+1) || line == fmt.Sprintf("%v", choice) {
+				return choice
+			}
+		}
+	}
+}
+
+func (p *prompt) printQuestion(def interface{}) {
+	color.New(color.FgCyan).Printf("%s", p.label)
+	if def != nil {
+		fmt.Printf(" (%v)", def)
+	}
+	fmt.Print(": ")
+}
+
+func (p *prompt) printHelp() {
+	if p.help == "" {
+		fmt.Println("(no help available)")
+		return
+	}
+
+	fmt.Println(p.help)
+}
@@ -0,0 +1,334 @@
+package template
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/timsolov/boilr/pkg/util/stringutil"
+)
+
+// AdoptAction describes what Adopt did (or, in dry-run mode, would do) to a
+// single file under a template's template/ directory.
+type AdoptAction int
+
+const (
+	// AdoptUnchanged means the template file already matches the project.
+	AdoptUnchanged AdoptAction = iota
+	// AdoptUpdated means an existing template file's contents were rewritten.
+	AdoptUpdated
+	// AdoptCreated means a new, non-templated file was copied into the template.
+	AdoptCreated
+	// AdoptDeleted means a template file whose rendered output no longer
+	// exists in the project was removed.
+	AdoptDeleted
+)
+
+func (a AdoptAction) String() string {
+	switch a {
+	case AdoptUpdated:
+		return "updated"
+	case AdoptCreated:
+		return "created"
+	case AdoptDeleted:
+		return "deleted"
+	default:
+		return "unchanged"
+	}
+}
+
+// AdoptChange reports what Adopt did to one template file, named by its
+// path relative to the template's template/ directory.
+type AdoptChange struct {
+	TemplateFile string
+	Action       AdoptAction
+}
+
+// Adopt reverse-engineers changes made to an existing rendered project back
+// into the template it was generated from. For every file under
+// projectPath it looks up the corresponding template source (by
+// re-executing the template's file-name templates with the template's own
+// default context), turns literal occurrences of project.json's default
+// values back into template placeholders, and updates the template file.
+// Files with no corresponding template source are copied in verbatim;
+// template files whose rendered counterpart is no longer present in
+// projectPath are removed. It is the inverse of dirTemplate.Execute.
+func Adopt(templatePath, projectPath string) ([]AdoptChange, error) {
+	return adopt(templatePath, projectPath, false)
+}
+
+// AdoptDryRun reports the changes Adopt would make without touching disk.
+func AdoptDryRun(templatePath, projectPath string) ([]AdoptChange, error) {
+	return adopt(templatePath, projectPath, true)
+}
+
+func adopt(templatePath, projectPath string, dryRun bool) ([]AdoptChange, error) {
+	tmpl, err := Get(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dt, ok := tmpl.(*dirTemplate)
+	if !ok {
+		return nil, fmt.Errorf("adopt: unsupported template implementation %T", tmpl)
+	}
+
+	dt.UseDefaultValues()
+
+	s, err := dt.newScope()
+	if err != nil {
+		return nil, err
+	}
+
+	renderedToSource, err := renderedFileNames(dt, s.funcs)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := contextDefaults(dt.Context)
+
+	var changes []AdoptChange
+
+	seenSource := make(map[string]bool, len(renderedToSource))
+
+	err = filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return err
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sourceRel, isTemplated := renderedToSource[rel]
+		if !isTemplated {
+			change, err := adoptNewFile(dt.Path, rel, contents, dryRun)
+			if err != nil {
+				return err
+			}
+
+			changes = append(changes, change)
+
+			return nil
+		}
+
+		seenSource[sourceRel] = true
+
+		change, err := adoptTemplatedFile(dt, s.funcs, defaults, sourceRel, contents, dryRun)
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, change)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sourceRel := range renderedToSource {
+		if seenSource[sourceRel] {
+			continue
+		}
+
+		if !dryRun {
+			if err := os.Remove(filepath.Join(dt.Path, sourceRel)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+
+		changes = append(changes, AdoptChange{TemplateFile: sourceRel, Action: AdoptDeleted})
+	}
+
+	return changes, nil
+}
+
+// renderedFileNames renders every template file's name against funcs
+// (bound with default values), returning a map from rendered relative path
+// to the template-relative source path it came from.
+func renderedFileNames(dt *dirTemplate, funcs template.FuncMap) (map[string]string, error) {
+	out := make(map[string]string)
+
+	err := filepath.Walk(dt.Path, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		oldName, err := filepath.Rel(dt.Path, filename)
+		if err != nil {
+			return err
+		}
+
+		newName, err := dt.renderName(funcs, oldName)
+		if err != nil {
+			return err
+		}
+
+		out[newName] = oldName
+
+		return nil
+	})
+
+	return out, err
+}
+
+func adoptNewFile(templateDir, rel string, contents []byte, dryRun bool) (AdoptChange, error) {
+	if dryRun {
+		return AdoptChange{TemplateFile: rel, Action: AdoptCreated}, nil
+	}
+
+	target := filepath.Join(templateDir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return AdoptChange{}, err
+	}
+
+	if err := ioutil.WriteFile(target, contents, 0644); err != nil {
+		return AdoptChange{}, err
+	}
+
+	return AdoptChange{TemplateFile: rel, Action: AdoptCreated}, nil
+}
+
+// contextDefaults flattens project.json's context into the string default
+// value each prompt/advanced-option key would contribute to a render,
+// including "advanced options" children. adoptTemplatedFile reverses these
+// out of rendered project content. It deliberately doesn't use a scope's
+// answers: those are only populated for keys a template actually renders,
+// which content-only or unreferenced keys never are.
+func contextDefaults(ctxt map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+
+	for key, val := range ctxt {
+		if m, ok := val.(map[string]interface{}); ok && isPromptSpec(m) {
+			spec := parsePromptSpec(m)
+			if s, ok := stringDefault(spec.Default); ok {
+				out[key] = os.Expand(s, os.Getenv)
+			}
+
+			continue
+		}
+
+		if childMap, ok := val.(map[string]interface{}); ok {
+			for childKey, childVal := range childMap {
+				if s, ok := stringDefault(childVal); ok {
+					out[childKey] = s
+				}
+			}
+
+			continue
+		}
+
+		if s, ok := stringDefault(val); ok {
+			out[key] = s
+		}
+	}
+
+	return out
+}
+
+// stringDefault extracts the string a context value would render as: the
+// value itself if it's already a string, or its first element if it's a
+// shorthand choice list.
+func stringDefault(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// adoptTemplatedFile turns literal occurrences of defaults' values back
+// into {{ KeyFunc }} placeholders within rendered, the project's current
+// contents for the file, and writes the result over the template source at
+// sourceRel if it differs. Values are substituted longest-first, so a short
+// default (e.g. "App") can't shadow a longer one that contains it (e.g.
+// "AppStore") and corrupt the reversed placeholder.
+func adoptTemplatedFile(dt *dirTemplate, funcs template.FuncMap, defaults map[string]string, sourceRel string, rendered []byte, dryRun bool) (AdoptChange, error) {
+	target := filepath.Join(dt.Path, sourceRel)
+
+	tmpl, err := dt.parsedContent(target)
+	if err != nil {
+		return AdoptChange{}, err
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return AdoptChange{}, err
+	}
+
+	buf := stringutil.NewString("")
+
+	if err := clone.Funcs(funcs).ExecuteTemplate(buf, filepath.Base(target), nil); err != nil {
+		return AdoptChange{}, err
+	}
+
+	if buf.String() == string(rendered) {
+		return AdoptChange{TemplateFile: sourceRel, Action: AdoptUnchanged}, nil
+	}
+
+	reversed := reverseDefaults(string(rendered), defaults)
+
+	existing, err := ioutil.ReadFile(target)
+	if err != nil {
+		return AdoptChange{}, err
+	}
+
+	if string(existing) == reversed {
+		return AdoptChange{TemplateFile: sourceRel, Action: AdoptUnchanged}, nil
+	}
+
+	if !dryRun {
+		if err := ioutil.WriteFile(target, []byte(reversed), 0644); err != nil {
+			return AdoptChange{}, err
+		}
+	}
+
+	return AdoptChange{TemplateFile: sourceRel, Action: AdoptUpdated}, nil
+}
+
+// reverseDefaults replaces literal occurrences of defaults' values in text
+// with {{Key}} placeholders, longest value first to avoid substring
+// collisions between defaults.
+func reverseDefaults(text string, defaults map[string]string) string {
+	type kv struct {
+		key, val string
+	}
+
+	pairs := make([]kv, 0, len(defaults))
+	for key, val := range defaults {
+		if val != "" {
+			pairs = append(pairs, kv{key, val})
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return len(pairs[i].val) > len(pairs[j].val)
+	})
+
+	// A single Replacer pass never rescans inserted placeholders, so a value
+	// that also occurs in another key's name can't rewrite that placeholder.
+	oldnew := make([]string, 0, 2*len(pairs))
+	for _, p := range pairs {
+		oldnew = append(oldnew, p.val, fmt.Sprintf("{{%s}}", p.key))
+	}
+
+	return strings.NewReplacer(oldnew...).Replace(text)
+}
@@ -0,0 +1,60 @@
+package template
+
+import "testing"
+
+func TestContextDefaults(t *testing.T) {
+	ctxt := map[string]interface{}{
+		"Name":  "my-app",
+		"Color": []interface{}{"blue", "red"},
+		"Env": map[string]interface{}{
+			"prompt":  "Env",
+			"default": "prod",
+		},
+		"Advanced": map[string]interface{}{
+			"staging": "staging-value",
+		},
+	}
+
+	defaults := contextDefaults(ctxt)
+
+	want := map[string]string{
+		"Name":    "my-app",
+		"Color":   "blue",
+		"Env":     "prod",
+		"staging": "staging-value",
+	}
+
+	for key, val := range want {
+		if defaults[key] != val {
+			t.Errorf("contextDefaults[%q] = %q, want %q", key, defaults[key], val)
+		}
+	}
+}
+
+func TestReverseDefaultsLongestValueFirst(t *testing.T) {
+	defaults := map[string]string{
+		"App":      "App",
+		"AppStore": "AppStore",
+	}
+
+	got := reverseDefaults("Welcome to AppStore, powered by App.", defaults)
+	want := "Welcome to {{AppStore}}, powered by {{App}}."
+
+	if got != want {
+		t.Errorf("reverseDefaults = %q, want %q", got, want)
+	}
+}
+
+func TestReverseDefaultsSkipsEmptyValues(t *testing.T) {
+	defaults := map[string]string{
+		"Optional": "",
+		"Name":     "my-app",
+	}
+
+	got := reverseDefaults("my-app", defaults)
+	want := "{{Name}}"
+
+	if got != want {
+		t.Errorf("reverseDefaults = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// ContextLoader loads a project's context (the variables used to fill in a
+// template, normally read from a project.* file) from path.
+type ContextLoader interface {
+	Load(path string) (map[string]interface{}, error)
+}
+
+// ContextLoaderFunc adapts a function to a ContextLoader.
+type ContextLoaderFunc func(path string) (map[string]interface{}, error)
+
+// Load calls f(path).
+func (f ContextLoaderFunc) Load(path string) (map[string]interface{}, error) {
+	return f(path)
+}
+
+var contextLoaders = map[string]ContextLoader{
+	".json": ContextLoaderFunc(loadJSONContext),
+	".toml": ContextLoaderFunc(loadTOMLContext),
+	".yaml": ContextLoaderFunc(loadYAMLContext),
+	".yml":  ContextLoaderFunc(loadYAMLContext),
+}
+
+// RegisterContextLoader registers a ContextLoader for project.* files whose
+// extension matches ext (including the leading dot, e.g. ".hcl"). It
+// overrides any loader already registered for ext, so downstream users of
+// boilr as a library can add formats without forking it.
+func RegisterContextLoader(ext string, l ContextLoader) {
+	contextLoaders[strings.ToLower(ext)] = l
+}
+
+// loadContext finds the project.* file under absPath and loads it with the
+// ContextLoader registered for its extension. It returns (nil, nil) if no
+// project.* file exists, since a template's context is optional.
+func loadContext(absPath string) (map[string]interface{}, error) {
+	matches, err := filepath.Glob(filepath.Join(absPath, "project.*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		loader, ok := contextLoaders[strings.ToLower(filepath.Ext(match))]
+		if !ok {
+			continue
+		}
+
+		return loader.Load(match)
+	}
+
+	return nil, nil
+}
+
+func loadJSONContext(path string) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ctxt map[string]interface{}
+	if err := json.Unmarshal(buf, &ctxt); err != nil {
+		return nil, err
+	}
+
+	return ctxt, nil
+}
+
+func loadTOMLContext(path string) (map[string]interface{}, error) {
+	var ctxt map[string]interface{}
+	if _, err := toml.DecodeFile(path, &ctxt); err != nil {
+		return nil, err
+	}
+
+	return ctxt, nil
+}
+
+func loadYAMLContext(path string) (map[string]interface{}, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(buf, &raw); err != nil {
+		return nil, err
+	}
+
+	ctxt, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("context: %s does not decode to an object", path)
+	}
+
+	return ctxt, nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} nodes that
+// gopkg.in/yaml.v2 produces into map[string]interface{}, recursively, so
+// the rest of the package can treat every loader's output the way
+// encoding/json would have produced it.
+func normalizeYAML(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			out[fmt.Sprintf("%v", key)] = normalizeYAML(val)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = normalizeYAML(val)
+		}
+
+		return out
+	default:
+		return v
+	}
+}
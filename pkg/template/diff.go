@@ -0,0 +1,102 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diff renders a unified-diff-style report comparing every file the plan
+// would overwrite against its current contents on disk.
+func (p *Plan) Diff() string {
+	var b strings.Builder
+
+	for _, entry := range p.Entries {
+		if entry.IsDir || entry.Action != ActionOverwrite {
+			continue
+		}
+
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", entry.Path, entry.Path)
+		b.WriteString(unifiedDiff(string(entry.OldContents), string(entry.Contents)))
+	}
+
+	return b.String()
+}
+
+// unifiedDiff produces a minimal +/- line diff of oldText and newText,
+// anchored on their longest common subsequence of lines.
+func unifiedDiff(oldText, newText string) string {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+	common := lcs(oldLines, newLines)
+
+	var b strings.Builder
+
+	i, j, k := 0, 0, 0
+	for k < len(common) {
+		for i < len(oldLines) && oldLines[i] != common[k] {
+			fmt.Fprintf(&b, "-%s\n", oldLines[i])
+			i++
+		}
+
+		for j < len(newLines) && newLines[j] != common[k] {
+			fmt.Fprintf(&b, "+%s\n", newLines[j])
+			j++
+		}
+
+		fmt.Fprintf(&b, " %s\n", common[k])
+		i++
+		j++
+		k++
+	}
+
+	for ; i < len(oldLines); i++ {
+		fmt.Fprintf(&b, "-%s\n", oldLines[i])
+	}
+
+	for ; j < len(newLines); j++ {
+		fmt.Fprintf(&b, "+%s\n", newLines[j])
+	}
+
+	return b.String()
+}
+
+// lcs returns the longest common subsequence of lines shared by a and b.
+func lcs(a, b []string) []string {
+	n, m := len(a), len(b)
+
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				dp[i][j] = dp[i+1][j+1] + 1
+			case dp[i+1][j] >= dp[i][j+1]:
+				dp[i][j] = dp[i+1][j]
+			default:
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return out
+}
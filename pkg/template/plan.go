@@ -0,0 +1,274 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"text/template"
+
+	"github.com/timsolov/boilr/pkg/util/stringutil"
+	"github.com/timsolov/boilr/pkg/util/tlog"
+)
+
+// FileAction describes what Plan.Apply will do for a single entry.
+type FileAction int
+
+const (
+	// ActionCreate means the target path doesn't exist yet and will be created.
+	ActionCreate FileAction = iota
+	// ActionOverwrite means the target path exists and its contents would change.
+	ActionOverwrite
+	// ActionSkip means nothing will be written, see PlanEntry.Reason.
+	ActionSkip
+)
+
+const (
+	// reasonUnchanged marks a skip where target already holds the rendered
+	// contents: Apply leaves it alone.
+	reasonUnchanged = "unchanged"
+	// reasonWhitespaceOnly marks a skip where the template rendered to
+	// nothing but whitespace: Apply removes any stale target, matching
+	// renderEntry's behavior of never leaving such a file on disk.
+	reasonWhitespaceOnly = "whitespace-only"
+)
+
+func (a FileAction) String() string {
+	switch a {
+	case ActionOverwrite:
+		return "overwrite"
+	case ActionSkip:
+		return "skip"
+	default:
+		return "create"
+	}
+}
+
+// PlanEntry is a single file or directory that executing a template would
+// produce, named by its path relative to the target directory.
+type PlanEntry struct {
+	Path        string
+	IsDir       bool
+	Action      FileAction
+	Reason      string // set when Action is ActionSkip, e.g. "whitespace-only"
+	Mode        os.FileMode
+	Contents    []byte // the rendered contents; nil for directories and unresolved files
+	OldContents []byte // the target's current contents, set when Action is ActionOverwrite
+}
+
+// Plan captures everything Execute would do for a given target directory
+// without touching disk: one PlanEntry per template file/directory, plus
+// variables templates reference that project.json doesn't define, and
+// project.json keys no template ever references. Execute builds a Plan and
+// immediately applies it, so dry-run callers and the real executor share
+// the exact same rendering and bookkeeping.
+type Plan struct {
+	Entries             []PlanEntry
+	UnresolvedVariables []string
+	UnusedContextKeys   []string
+}
+
+var errFuncNotDefined = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// Plan renders t's templates against dirPrefix without writing anything to
+// disk, binding prompts exactly as Execute would (so it still prompts the
+// user unless UseDefaultValues was called first). Each call builds its own
+// scope, so Plan and Execute calls never share prompt answers.
+func (t *dirTemplate) Plan(dirPrefix string) (*Plan, error) {
+	s, err := t.newScope()
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(t.Context))
+	for key := range t.Context {
+		if fn, ok := s.funcs[key]; ok {
+			s.funcs[key] = trackUsage(key, used, fn)
+		}
+	}
+
+	plan := &Plan{}
+
+	err = filepath.Walk(t.Path, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		entry, unresolved, err := t.planEntry(s.funcs, dirPrefix, filename, info)
+		if err != nil {
+			return err
+		}
+
+		plan.Entries = append(plan.Entries, entry)
+		plan.UnresolvedVariables = appendMissing(plan.UnresolvedVariables, unresolved...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range t.Context {
+		if !used[key] {
+			plan.UnusedContextKeys = append(plan.UnusedContextKeys, key)
+		}
+	}
+
+	sort.Strings(plan.UnresolvedVariables)
+	sort.Strings(plan.UnusedContextKeys)
+
+	return plan, nil
+}
+
+// trackUsage wraps a bound context key's zero-arg FuncMap entry, marking
+// key used the moment the template engine calls it. It's built with
+// reflect rather than a fixed func() interface{} signature because
+// bindDefault binds context keys under a handful of different zero-arg
+// signatures (e.g. func() bool for an advanced-options group's toggle),
+// all of which need to show up correctly in Plan.UnusedContextKeys.
+func trackUsage(key string, used map[string]bool, fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+
+	wrapped := reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		used[key] = true
+		return v.Call(args)
+	})
+
+	return wrapped.Interface()
+}
+
+func appendMissing(have []string, want ...string) []string {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			have = append(have, w)
+		}
+	}
+
+	return have
+}
+
+func (t *dirTemplate) planEntry(funcs template.FuncMap, dirPrefix, filename string, info os.FileInfo) (PlanEntry, []string, error) {
+	oldName, err := filepath.Rel(t.Path, filename)
+	if err != nil {
+		return PlanEntry{}, nil, err
+	}
+
+	newName, err := t.renderName(funcs, oldName)
+	if err != nil {
+		return PlanEntry{}, nil, err
+	}
+
+	target := filepath.Join(dirPrefix, newName)
+
+	if info.IsDir() {
+		action := ActionCreate
+		if _, err := os.Stat(target); err == nil {
+			action = ActionSkip
+		}
+
+		return PlanEntry{Path: newName, IsDir: true, Action: action}, nil, nil
+	}
+
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		return PlanEntry{}, nil, err
+	}
+
+	tmpl, parseErr := t.parsedContent(filename)
+	if parseErr != nil {
+		if m := errFuncNotDefined.FindStringSubmatch(parseErr.Error()); m != nil {
+			return PlanEntry{Path: newName, Mode: fi.Mode(), Action: ActionSkip, Reason: fmt.Sprintf("unresolved variable %q", m[1])}, []string{m[1]}, nil
+		}
+
+		return PlanEntry{}, nil, parseErr
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return PlanEntry{}, nil, err
+	}
+
+	buf := stringutil.NewString("")
+
+	if err := clone.Funcs(funcs).ExecuteTemplate(buf, filepath.Base(filename), nil); err != nil {
+		return PlanEntry{}, nil, err
+	}
+
+	contents := []byte(buf.String())
+
+	if isOnlyWhitespace(contents) {
+		return PlanEntry{Path: newName, Mode: fi.Mode(), Action: ActionSkip, Reason: reasonWhitespaceOnly}, nil, nil
+	}
+
+	existing, err := ioutil.ReadFile(target)
+	if err != nil {
+		return PlanEntry{Path: newName, Mode: fi.Mode(), Action: ActionCreate, Contents: contents}, nil, nil
+	}
+
+	if bytes.Equal(existing, contents) {
+		return PlanEntry{Path: newName, Mode: fi.Mode(), Action: ActionSkip, Reason: reasonUnchanged, Contents: contents}, nil, nil
+	}
+
+	return PlanEntry{Path: newName, Mode: fi.Mode(), Action: ActionOverwrite, Contents: contents, OldContents: existing}, nil, nil
+}
+
+// Apply writes every ActionCreate/ActionOverwrite entry in the plan under
+// dirPrefix. ActionSkip entries are left untouched, except that a
+// whitespace-only entry's existing target is removed.
+func (p *Plan) Apply(dirPrefix string) error {
+	for _, entry := range p.Entries {
+		target := filepath.Join(dirPrefix, entry.Path)
+
+		if entry.IsDir {
+			if entry.Action == ActionSkip {
+				continue
+			}
+
+			if err := os.Mkdir(target, 0755); err != nil && !os.IsExist(err) {
+				return err
+			}
+
+			continue
+		}
+
+		if entry.Action == ActionSkip {
+			if entry.Reason == reasonWhitespaceOnly {
+				if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := ioutil.WriteFile(target, entry.Contents, entry.Mode); err != nil {
+			return err
+		}
+
+		verb := "Created"
+		if entry.Action == ActionOverwrite {
+			verb = "Updated"
+		}
+
+		tlog.Success(fmt.Sprintf("%s %s", verb, entry.Path))
+	}
+
+	return nil
+}
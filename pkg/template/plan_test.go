@@ -0,0 +1,76 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func TestPlanReportsUnresolvedAndUnusedKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boilr-plan-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "known.txt"), []byte("{{Known}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.txt"), []byte("{{Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dt := &dirTemplate{
+		Path:    dir,
+		Context: map[string]interface{}{"Known": "value", "Unused": "value"},
+		FuncMap: template.FuncMap{},
+	}
+	dt.UseDefaultValues()
+
+	plan, err := dt.Plan(dir)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+
+	if len(plan.UnresolvedVariables) != 1 || plan.UnresolvedVariables[0] != "Missing" {
+		t.Errorf("UnresolvedVariables = %v, want [Missing]", plan.UnresolvedVariables)
+	}
+
+	if len(plan.UnusedContextKeys) != 1 || plan.UnusedContextKeys[0] != "Unused" {
+		t.Errorf("UnusedContextKeys = %v, want [Unused]", plan.UnusedContextKeys)
+	}
+}
+
+func TestExecuteFailsOnUnresolvedVariable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boilr-execute-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "good.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "broken.txt"), []byte("{{Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dt := &dirTemplate{
+		Path:    dir,
+		Context: map[string]interface{}{},
+		FuncMap: template.FuncMap{},
+	}
+	dt.UseDefaultValues()
+
+	target, err := ioutil.TempDir("", "boilr-execute-target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(target)
+
+	if err := dt.Execute(target); err == nil {
+		t.Fatal("Execute: want an error for an unresolved variable, got nil")
+	}
+}
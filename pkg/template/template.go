@@ -1,12 +1,13 @@
 package template
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
@@ -22,6 +23,15 @@ type Interface interface {
 	// Executes the template on the given target directory path.
 	Execute(string) error
 
+	// Executes the template on the given target directory path, then keeps
+	// re-executing changed files as the template's source tree is edited,
+	// until stop is closed.
+	ExecuteWatch(string, <-chan struct{}) error
+
+	// Plan renders the template against the given target directory without
+	// writing anything to disk, reporting what Execute would do.
+	Plan(string) (*Plan, error)
+
 	// If used, the template will execute using default values.
 	UseDefaultValues()
 
@@ -29,7 +39,7 @@ type Interface interface {
 	Info() Metadata
 }
 
-func (t dirTemplate) Info() Metadata {
+func (t *dirTemplate) Info() Metadata {
 	return t.Metadata
 }
 
@@ -40,30 +50,7 @@ func Get(path string) (Interface, error) {
 		return nil, err
 	}
 
-	// TODO make context optional
-	ctxt, err := func(fname string) (map[string]interface{}, error) {
-		f, err := os.Open(fname)
-		if err != nil {
-			if os.IsNotExist(err) {
-				return nil, nil
-			}
-
-			return nil, err
-		}
-		defer f.Close()
-
-		buf, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, err
-		}
-
-		var metadata map[string]interface{}
-		if err := json.Unmarshal(buf, &metadata); err != nil {
-			return nil, err
-		}
-
-		return metadata, nil
-	}(filepath.Join(absPath, boilr.ContextFileName))
+	ctxt, err := loadContext(absPath)
 	if err != nil {
 		return nil, err
 	}
@@ -84,9 +71,14 @@ func Get(path string) (Interface, error) {
 		return m, nil
 	}()
 
+	baseFuncMap := make(template.FuncMap, len(FuncMap))
+	for name, fn := range FuncMap {
+		baseFuncMap[name] = fn
+	}
+
 	return &dirTemplate{
 		Context:  ctxt,
-		FuncMap:  FuncMap,
+		FuncMap:  baseFuncMap,
 		Path:     filepath.Join(absPath, boilr.TemplateDirName),
 		Metadata: md,
 	}, err
@@ -100,129 +92,529 @@ type dirTemplate struct {
 
 	alignment         string
 	ShouldUseDefaults bool
+
+	cacheMu      sync.Mutex
+	nameCache    map[string]*template.Template
+	contentCache map[string]*template.Template
 }
 
 func (t *dirTemplate) UseDefaultValues() {
 	t.ShouldUseDefaults = true
 }
 
-func (t *dirTemplate) BindPrompts() {
-	for parentKey := range t.Context {
-		if t.ShouldUseDefaults {
-			handleBindDefaults(t, parentKey)
-		} else {
-			handleBindPrompts(t, parentKey)
+// promptSpec is the object form of a project.json entry, giving a variable
+// a prompt label, help text, a default (possibly referencing $VAR / other
+// answers) and an ordering relative to other variables. Hidden always
+// takes the default instead of prompting; When names another key whose
+// answered value must not be the boolean false for this one to prompt at
+// all, otherwise it also takes the default.
+type promptSpec struct {
+	Prompt    string
+	Help      string
+	Default   interface{}
+	DependsOn []string
+	Hidden    bool
+	When      string
+}
+
+// promptSpecKeys are the reserved keys that mark a context entry as using
+// the object form rather than the nested "advanced options" shorthand.
+var promptSpecKeys = map[string]bool{
+	"prompt":     true,
+	"help":       true,
+	"default":    true,
+	"depends_on": true,
+	"hidden":     true,
+	"when":       true,
+}
+
+// isPromptSpec reports whether m is the object form of a context entry
+// rather than an "advanced options" group of plain child defaults. It
+// requires every key in m to be a reserved promptSpec key: matching on
+// "at least one" would misfire on a legacy group that happens to have a
+// child literally named e.g. "default" (as in {"default": "prod",
+// "staging": "staging"}), silently dropping its other children.
+func isPromptSpec(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+
+	for key := range m {
+		if !promptSpecKeys[key] {
+			return false
 		}
 	}
+
+	return true
 }
 
-// Execute fills the template with the project metadata.
-func (t *dirTemplate) Execute(dirPrefix string) error {
-	t.BindPrompts()
+func parsePromptSpec(m map[string]interface{}) promptSpec {
+	var spec promptSpec
 
-	isOnlyWhitespace := func(buf []byte) bool {
-		wsre := regexp.MustCompile(`\S`)
+	if v, ok := m["prompt"].(string); ok {
+		spec.Prompt = v
+	}
+	if v, ok := m["help"].(string); ok {
+		spec.Help = v
+	}
+	if v, ok := m["default"]; ok {
+		spec.Default = v
+	}
+	if v, ok := m["depends_on"].([]interface{}); ok {
+		for _, dep := range v {
+			if s, ok := dep.(string); ok {
+				spec.DependsOn = append(spec.DependsOn, s)
+			}
+		}
+	}
+	if v, ok := m["hidden"].(bool); ok {
+		spec.Hidden = v
+	}
+	if v, ok := m["when"].(string); ok {
+		spec.When = v
+	}
+
+	return spec
+}
 
-		return !wsre.Match(buf)
+// orderedContextKeys topologically sorts the top-level context keys so that
+// a key named in another key's depends_on is installed (and so answered)
+// first. It fails with an error if depends_on references an unknown key or
+// forms a cycle.
+func orderedContextKeys(ctxt map[string]interface{}) ([]string, error) {
+	specs := make(map[string]promptSpec, len(ctxt))
+	for key, val := range ctxt {
+		if m, ok := val.(map[string]interface{}); ok && isPromptSpec(m) {
+			spec := parsePromptSpec(m)
+			if spec.When != "" {
+				// A key gated by when must be bound (and so answered)
+				// before the key it's gated on, same as an explicit
+				// depends_on, so bindPrompt can read the other key's
+				// answer when deciding whether to prompt.
+				spec.DependsOn = appendMissing(spec.DependsOn, spec.When)
+			}
+			specs[key] = spec
+		}
 	}
 
-	// TODO create io.ReadWriter from string
-	// TODO refactor name manipulation
-	return filepath.Walk(t.Path, func(filename string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(ctxt))
+	order := make([]string, 0, len(ctxt))
+
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("template: depends_on cycle detected at key %q", key)
 		}
 
-		// Path relative to the root of the template directory
-		oldName, err := filepath.Rel(t.Path, filename)
-		if err != nil {
-			return err
+		state[key] = visiting
+
+		for _, dep := range specs[key].DependsOn {
+			if _, ok := ctxt[dep]; !ok {
+				return fmt.Errorf("template: key %q depends_on unknown key %q", key, dep)
+			}
+
+			if err := visit(dep); err != nil {
+				return err
+			}
 		}
 
-		buf := stringutil.NewString("")
+		state[key] = visited
+		order = append(order, key)
 
-		// TODO translate errors into meaningful ones
-		fnameTmpl := template.Must(template.
-			New("file name template").
-			Option(Options...).
-			Funcs(sprig.TxtFuncMap()).
-			Funcs(FuncMap).
-			Parse(oldName))
+		return nil
+	}
 
-		if err := fnameTmpl.Execute(buf, nil); err != nil {
-			return err
+	for key := range ctxt {
+		if err := visit(key); err != nil {
+			return nil, err
 		}
+	}
 
-		newName := buf.String()
+	return order, nil
+}
 
-		target := filepath.Join(dirPrefix, newName)
+// CheckDependencies loads the template at path and verifies its context's
+// depends_on graph has no cycles and no references to unknown keys,
+// without prompting or touching disk. It's what lets `boilr validate`
+// catch depends_on mistakes that MustValidateTemplate's syntactic checks
+// don't, without requiring --dry-run.
+func CheckDependencies(path string) error {
+	tmpl, err := Get(path)
+	if err != nil {
+		return err
+	}
 
-		if info.IsDir() {
-			if err := os.Mkdir(target, 0755); err != nil {
-				if !os.IsExist(err) {
-					return err
-				}
+	dt, ok := tmpl.(*dirTemplate)
+	if !ok {
+		return fmt.Errorf("template: unsupported template implementation %T", tmpl)
+	}
+
+	_, err = orderedContextKeys(dt.Context)
+
+	return err
+}
+
+// scope is the set of template functions and answers built fresh for a
+// single execution. Keeping it off dirTemplate (rather than mutating
+// t.FuncMap in place, as earlier versions did) is what lets the same
+// *dirTemplate be executed concurrently, or several times over with
+// different answers, without one render's prompts leaking into another's.
+type scope struct {
+	funcs   template.FuncMap
+	answers map[string]interface{}
+}
+
+// expandDefault expands $VAR / ${VAR} references in a string default using
+// the process environment and the prompts already answered in this scope,
+// exposed as TMPL_PROMPT_<UPPERKEY>.
+func (s *scope) expandDefault(def interface{}) interface{} {
+	str, ok := def.(string)
+	if !ok {
+		return def
+	}
+
+	return os.Expand(str, func(name string) string {
+		for key, val := range s.answers {
+			if "TMPL_PROMPT_"+strings.ToUpper(key) == name {
+				return fmt.Sprintf("%v", val)
 			}
+		}
+
+		return os.Getenv(name)
+	})
+}
+
+func (s *scope) recordAnswer(key string, val interface{}) interface{} {
+	s.answers[key] = val
+
+	return val
+}
+
+// evalCondition resolves a when reference to a boolean by calling the
+// other key's already-bound FuncMap entry (orderedContextKeys guarantees
+// it was bound first). It defaults to true, i.e. keep prompting, when key
+// isn't bound or doesn't resolve to a bool, since failing open is safer
+// than silently hiding a prompt the template author didn't mean to hide.
+func (s *scope) evalCondition(key string) bool {
+	fn, ok := s.funcs[key]
+	if !ok {
+		return true
+	}
+
+	switch f := fn.(type) {
+	case func() interface{}:
+		v, ok := f().(bool)
+		return !ok || v
+	case func() bool:
+		return f()
+	default:
+		return true
+	}
+}
+
+// newScope binds every context key to a function in a freshly built
+// FuncMap, topologically ordered by depends_on, for one render. It never
+// touches t.FuncMap, so repeated or concurrent calls don't interfere.
+func (t *dirTemplate) newScope() (*scope, error) {
+	keys, err := orderedContextKeys(t.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scope{
+		funcs:   make(template.FuncMap, len(t.FuncMap)+len(t.Context)),
+		answers: make(map[string]interface{}, len(t.Context)),
+	}
+
+	for name, fn := range t.FuncMap {
+		s.funcs[name] = fn
+	}
+
+	for _, parentKey := range keys {
+		if t.ShouldUseDefaults {
+			bindDefault(s, parentKey, t.Context[parentKey])
 		} else {
-			fi, err := os.Lstat(filename)
-			if err != nil {
-				return err
-			}
+			bindPrompt(s, parentKey, t.Context[parentKey])
+		}
+	}
 
-			// Delete target file if it exists
-			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
-				return err
-			}
+	return s, nil
+}
 
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
-			if err != nil {
-				return err
+// Execute fills the template with the project metadata. It builds a Plan
+// and applies it immediately, so it shares its rendering and bookkeeping
+// with Plan-based dry runs.
+func (t *dirTemplate) Execute(dirPrefix string) error {
+	plan, err := t.Plan(dirPrefix)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.UnresolvedVariables) > 0 {
+		return fmt.Errorf("template: unresolved variable(s): %s", strings.Join(plan.UnresolvedVariables, ", "))
+	}
+
+	return plan.Apply(dirPrefix)
+}
+
+func isOnlyWhitespace(buf []byte) bool {
+	wsre := regexp.MustCompile(`\S`)
+
+	return !wsre.Match(buf)
+}
+
+// validationFuncMap returns a FuncMap with a stand-in entry for every
+// context key (in addition to t's base functions), sufficient to parse any
+// template referencing them. Parsing against this map, rather than a real
+// scope's, is what lets t.parsedName/t.parsedContent cache the parsed
+// *template.Template once in the template's lifetime: the set of function
+// names a template may call never changes between renders, only the values
+// those functions return.
+func (t *dirTemplate) validationFuncMap() template.FuncMap {
+	fm := make(template.FuncMap, len(t.FuncMap)+len(t.Context)*2)
+	for name, fn := range t.FuncMap {
+		fm[name] = fn
+	}
+
+	noop := func() interface{} { return nil }
+
+	for key, val := range t.Context {
+		if childMap, ok := val.(map[string]interface{}); ok && !isPromptSpec(childMap) {
+			fm[key] = noop
+
+			for childKey := range childMap {
+				fm[childKey] = noop
 			}
-			defer f.Close()
 
-			defer func(fname string) {
-				contents, err := ioutil.ReadFile(fname)
-				if err != nil {
-					tlog.Debug(fmt.Sprintf("couldn't read the contents of file %q, got error %q", fname, err))
-					return
-				}
+			continue
+		}
 
-				if isOnlyWhitespace(contents) {
-					os.Remove(fname)
-					return
-				}
-			}(f.Name())
+		fm[key] = noop
+	}
+
+	return fm
+}
 
-			contentsTmpl := template.Must(template.
-				New("file contents template").
-				Option(Options...).
-				Funcs(sprig.TxtFuncMap()).
-				Funcs(FuncMap).
-				ParseFiles(filename))
+// parsedName returns the cached *template.Template for the file-name
+// template at oldName (relative to t.Path), parsing and caching it on
+// first use.
+func (t *dirTemplate) parsedName(oldName string) (*template.Template, error) {
+	t.cacheMu.Lock()
+	defer t.cacheMu.Unlock()
 
-			fileTemplateName := filepath.Base(filename)
+	if tmpl, ok := t.nameCache[oldName]; ok {
+		return tmpl, nil
+	}
 
-			if err := contentsTmpl.ExecuteTemplate(f, fileTemplateName, nil); err != nil {
+	// TODO translate errors into meaningful ones
+	tmpl, err := template.
+		New("file name template").
+		Option(Options...).
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(t.validationFuncMap()).
+		Parse(oldName)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.nameCache == nil {
+		t.nameCache = make(map[string]*template.Template)
+	}
+	t.nameCache[oldName] = tmpl
+
+	return tmpl, nil
+}
+
+// parsedContent returns the cached *template.Template for the file at
+// filename (an absolute path under t.Path), parsing and caching it on
+// first use.
+func (t *dirTemplate) parsedContent(filename string) (*template.Template, error) {
+	t.cacheMu.Lock()
+	defer t.cacheMu.Unlock()
+
+	if tmpl, ok := t.contentCache[filename]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := template.
+		New("file contents template").
+		Option(Options...).
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(t.validationFuncMap()).
+		ParseFiles(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.contentCache == nil {
+		t.contentCache = make(map[string]*template.Template)
+	}
+	t.contentCache[filename] = tmpl
+
+	return tmpl, nil
+}
+
+// renderName executes the cached file-name template for a path relative to
+// t.Path against funcs, returning the name it renders to.
+func (t *dirTemplate) renderName(funcs template.FuncMap, oldName string) (string, error) {
+	tmpl, err := t.parsedName(oldName)
+	if err != nil {
+		return "", err
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+
+	buf := stringutil.NewString("")
+
+	if err := clone.Funcs(funcs).Execute(buf, nil); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// renderEntry renders the single template file or directory at filename
+// (an absolute path under t.Path) into dirPrefix, using funcs to resolve
+// the template's functions.
+func (t *dirTemplate) renderEntry(funcs template.FuncMap, dirPrefix, filename string, info os.FileInfo) error {
+	// Path relative to the root of the template directory
+	oldName, err := filepath.Rel(t.Path, filename)
+	if err != nil {
+		return err
+	}
+
+	newName, err := t.renderName(funcs, oldName)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(dirPrefix, newName)
+
+	if info.IsDir() {
+		if err := os.Mkdir(target, 0755); err != nil {
+			if !os.IsExist(err) {
 				return err
 			}
+		}
 
-			if !t.ShouldUseDefaults {
-				tlog.Success(fmt.Sprintf("Created %s", newName))
-			}
+		return nil
+	}
+
+	fi, err := os.Lstat(filename)
+	if err != nil {
+		return err
+	}
+
+	// Delete target file if it exists
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fi.Mode())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	defer func(fname string) {
+		contents, err := ioutil.ReadFile(fname)
+		if err != nil {
+			tlog.Debug(fmt.Sprintf("couldn't read the contents of file %q, got error %q", fname, err))
+			return
+		}
+
+		if isOnlyWhitespace(contents) {
+			os.Remove(fname)
+			return
 		}
+	}(f.Name())
+
+	tmpl, err := t.parsedContent(filename)
+	if err != nil {
+		return err
+	}
+
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+
+	if err := clone.Funcs(funcs).ExecuteTemplate(f, filepath.Base(filename), nil); err != nil {
+		return err
+	}
+
+	if !t.ShouldUseDefaults {
+		tlog.Success(fmt.Sprintf("Created %s", newName))
+	}
+
+	return nil
+}
+
+// invalidateCache evicts any cached parsed template for the file at
+// filename (an absolute path under t.Path), so the next render re-parses
+// it from disk instead of reusing stale content. ExecuteWatch calls this
+// whenever a template source changes, since parsedName/parsedContent
+// otherwise cache a file's parse tree for t's whole lifetime.
+func (t *dirTemplate) invalidateCache(filename string) error {
+	oldName, err := filepath.Rel(t.Path, filename)
+	if err != nil {
+		return err
+	}
 
+	t.cacheMu.Lock()
+	delete(t.nameCache, oldName)
+	delete(t.contentCache, filename)
+	t.cacheMu.Unlock()
+
+	return nil
+}
+
+// removeRendered deletes the rendered output under dirPrefix corresponding
+// to the now-removed template source at oldName (relative to t.Path).
+func (t *dirTemplate) removeRendered(funcs template.FuncMap, dirPrefix, oldName string) error {
+	newName, err := t.renderName(funcs, oldName)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(filepath.Join(dirPrefix, newName))
+	if os.IsNotExist(err) {
 		return nil
-	})
+	}
+
+	return err
 }
 
-func handleBindDefaults(t *dirTemplate, parentKey string) {
-	if childMap, ok := t.Context[parentKey].(map[string]interface{}); ok {
+func bindDefault(s *scope, parentKey string, val interface{}) {
+	if m, ok := val.(map[string]interface{}); ok && isPromptSpec(m) {
+		spec := parsePromptSpec(m)
+
+		s.funcs[parentKey] = func() interface{} {
+			return s.recordAnswer(parentKey, s.expandDefault(spec.Default))
+		}
+
+		return
+	}
+
+	if childMap, ok := val.(map[string]interface{}); ok {
 		if len(childMap) > 0 {
-			t.FuncMap[parentKey] = func() bool { return false }
+			s.funcs[parentKey] = func() bool { return false }
 		}
 
-		for childKey := range childMap {
-			t.FuncMap[childKey] = func(val interface{}) func() interface{} {
+		for childKey, childVal := range childMap {
+			s.funcs[childKey] = func(val interface{}) func() interface{} {
 				return func() interface{} {
 					switch val := val.(type) {
 					// First is the default value if it's a slice
@@ -232,10 +624,10 @@ func handleBindDefaults(t *dirTemplate, parentKey string) {
 
 					return val
 				}
-			}(childMap[childKey])
+			}(childVal)
 		}
 	} else {
-		t.FuncMap[parentKey] = func(val interface{}) func() interface{} {
+		s.funcs[parentKey] = func(val interface{}) func() interface{} {
 			return func() interface{} {
 				switch val := val.(type) {
 				// First is the default value if it's a slice
@@ -245,26 +637,51 @@ func handleBindDefaults(t *dirTemplate, parentKey string) {
 
 				return val
 			}
-		}(t.Context[parentKey])
+		}(val)
 	}
 }
 
-func handleBindPrompts(t *dirTemplate, parentKey string) {
-	if childMap, ok := t.Context[parentKey].(map[string]interface{}); ok {
+func bindPrompt(s *scope, parentKey string, val interface{}) {
+	if m, ok := val.(map[string]interface{}); ok && isPromptSpec(m) {
+		spec := parsePromptSpec(m)
+
+		label := spec.Prompt
+		if label == "" {
+			label = parentKey
+		}
+
+		childPrompt := prompt.New(parentKey, s.expandDefault(spec.Default), prompt.WithLabel(label), prompt.WithHelp(spec.Help))
+
+		// The hidden/when check is deferred to call time, inside the
+		// closure itself, rather than resolved here in the bind loop: a
+		// template that never calls {{ParentKey}} must never trigger its
+		// (or its when-dependency's) prompt, same as any other key.
+		s.funcs[parentKey] = func() interface{} {
+			if spec.Hidden || (spec.When != "" && !s.evalCondition(spec.When)) {
+				return s.recordAnswer(parentKey, s.expandDefault(spec.Default))
+			}
+
+			return s.recordAnswer(parentKey, childPrompt())
+		}
+
+		return
+	}
+
+	if childMap, ok := val.(map[string]interface{}); ok {
 		advancedMode := prompt.New(parentKey, false)
 
 		if len(childMap) > 0 {
-			t.FuncMap[parentKey] = func(a func() interface{}) func() interface{} {
+			s.funcs[parentKey] = func(a func() interface{}) func() interface{} {
 				return func() interface{} {
 					return advancedMode()
 				}
 			}(advancedMode)
 		}
 
-		for childKey := range childMap {
-			childPrompt := prompt.New(childKey, childMap[childKey])
+		for childKey, childVal := range childMap {
+			childPrompt := prompt.New(childKey, childVal)
 
-			t.FuncMap[childKey] = func(val interface{}, p func() interface{}) func() interface{} {
+			s.funcs[childKey] = func(val interface{}, p func() interface{}) func() interface{} {
 				return func() interface{} {
 					if isAdvanced := advancedMode().(bool); isAdvanced {
 						return p()
@@ -272,9 +689,13 @@ func handleBindPrompts(t *dirTemplate, parentKey string) {
 
 					return val
 				}
-			}(childMap[childKey], childPrompt)
+			}(childVal, childPrompt)
 		}
 	} else {
-		t.FuncMap[parentKey] = prompt.New(parentKey, t.Context[parentKey])
+		childPrompt := prompt.New(parentKey, val)
+
+		s.funcs[parentKey] = func() interface{} {
+			return s.recordAnswer(parentKey, childPrompt())
+		}
 	}
 }
@@ -0,0 +1,124 @@
+package template
+
+import "testing"
+
+func TestOrderedContextKeysRespectsDependsOn(t *testing.T) {
+	ctxt := map[string]interface{}{
+		"A": "a",
+		"B": map[string]interface{}{
+			"prompt":     "B",
+			"default":    "b",
+			"depends_on": []interface{}{"A"},
+		},
+		"C": map[string]interface{}{
+			"prompt":     "C",
+			"default":    "c",
+			"depends_on": []interface{}{"B"},
+		},
+	}
+
+	order, err := orderedContextKeys(ctxt)
+	if err != nil {
+		t.Fatalf("orderedContextKeys: %v", err)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, key := range order {
+		pos[key] = i
+	}
+
+	if pos["A"] > pos["B"] {
+		t.Errorf("A must be ordered before B, got order %v", order)
+	}
+	if pos["B"] > pos["C"] {
+		t.Errorf("B must be ordered before C, got order %v", order)
+	}
+}
+
+func TestOrderedContextKeysDetectsCycle(t *testing.T) {
+	ctxt := map[string]interface{}{
+		"A": map[string]interface{}{
+			"prompt":     "A",
+			"default":    "a",
+			"depends_on": []interface{}{"B"},
+		},
+		"B": map[string]interface{}{
+			"prompt":     "B",
+			"default":    "b",
+			"depends_on": []interface{}{"A"},
+		},
+	}
+
+	if _, err := orderedContextKeys(ctxt); err == nil {
+		t.Fatal("orderedContextKeys: want error for a depends_on cycle, got nil")
+	}
+}
+
+func TestOrderedContextKeysRejectsUnknownDependency(t *testing.T) {
+	ctxt := map[string]interface{}{
+		"A": map[string]interface{}{
+			"prompt":     "A",
+			"default":    "a",
+			"depends_on": []interface{}{"Missing"},
+		},
+	}
+
+	if _, err := orderedContextKeys(ctxt); err == nil {
+		t.Fatal("orderedContextKeys: want error for an unknown depends_on key, got nil")
+	}
+}
+
+func TestOrderedContextKeysFollowsWhen(t *testing.T) {
+	ctxt := map[string]interface{}{
+		"A": "a",
+		"B": map[string]interface{}{
+			"prompt":  "B",
+			"default": "b",
+			"when":    "A",
+		},
+	}
+
+	order, err := orderedContextKeys(ctxt)
+	if err != nil {
+		t.Fatalf("orderedContextKeys: %v", err)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, key := range order {
+		pos[key] = i
+	}
+
+	if pos["A"] > pos["B"] {
+		t.Errorf("A must be ordered before B since B's when references it, got order %v", order)
+	}
+}
+
+func TestIsPromptSpecRequiresOnlyReservedKeys(t *testing.T) {
+	cases := []struct {
+		name string
+		m    map[string]interface{}
+		want bool
+	}{
+		{
+			name: "object form",
+			m:    map[string]interface{}{"prompt": "Env", "default": "prod"},
+			want: true,
+		},
+		{
+			name: "advanced options group whose child collides with a reserved key",
+			m:    map[string]interface{}{"default": "prod", "staging": "staging"},
+			want: false,
+		},
+		{
+			name: "empty map",
+			m:    map[string]interface{}{},
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		if got := isPromptSpec(tc.m); got != tc.want {
+			t.Errorf("%s: isPromptSpec(%v) = %v, want %v", tc.name, tc.m, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/timsolov/boilr/pkg/util/tlog"
+)
+
+// watchDebounce is how long ExecuteWatch waits for a burst of filesystem
+// events to settle before re-rendering.
+const watchDebounce = 200 * time.Millisecond
+
+// ExecuteWatch renders the template into dirPrefix like Execute, keeping
+// the answered context in memory for the whole watch session (one scope,
+// built once, rather than Execute's fresh one per call), then watches
+// t.Path for changes and incrementally re-renders whenever a template file
+// is created, modified or removed. It returns nil once stop is closed.
+func (t *dirTemplate) ExecuteWatch(dirPrefix string, stop <-chan struct{}) error {
+	s, err := t.newScope()
+	if err != nil {
+		return err
+	}
+
+	err = filepath.Walk(t.Path, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return t.renderEntry(s.funcs, dirPrefix, filename, info)
+	})
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addRecursive(watcher, t.Path); err != nil {
+		return err
+	}
+
+	pending := make(map[string]fsnotify.Op)
+	debounce := time.NewTimer(watchDebounce)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			pending[event.Name] = event.Op
+			debounce.Reset(watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			tlog.Debug(fmt.Sprintf("watch: %s", err))
+
+		case <-debounce.C:
+			batch := pending
+			pending = make(map[string]fsnotify.Op)
+
+			if err := t.applyWatchBatch(s.funcs, dirPrefix, watcher, batch); err != nil {
+				tlog.Debug(fmt.Sprintf("watch: %s", err))
+			}
+		}
+	}
+}
+
+// applyWatchBatch re-renders or deletes every changed template file in
+// batch, and keeps the fsnotify watch list in sync with directories that
+// were created or removed.
+func (t *dirTemplate) applyWatchBatch(funcs template.FuncMap, dirPrefix string, watcher *fsnotify.Watcher, batch map[string]fsnotify.Op) error {
+	for filename, op := range batch {
+		if err := t.invalidateCache(filename); err != nil {
+			return err
+		}
+
+		if op&fsnotify.Remove != 0 || op&fsnotify.Rename != 0 {
+			oldName, err := filepath.Rel(t.Path, filename)
+			if err != nil {
+				return err
+			}
+
+			if err := t.removeRendered(funcs, dirPrefix, oldName); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		info, err := os.Stat(filename)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return err
+		}
+
+		if info.IsDir() {
+			if err := addRecursive(watcher, filename); err != nil {
+				return err
+			}
+		}
+
+		if err := t.renderEntry(funcs, dirPrefix, filename, info); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		tlog.Success(fmt.Sprintf("Re-rendered %s", filename))
+	}
+
+	return nil
+}
+
+// addRecursive adds root and every directory beneath it to watcher, since
+// fsnotify only watches the directories it's explicitly told about.
+func addRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+
+		return nil
+	})
+}